@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backend abstracts the mechanism used to talk to a RAID controller: shelling
+// out to a CLI tool, querying iLO over Redfish, or polling SNMP. Collect()
+// only ever depends on this interface, so adding a new way to reach the
+// hardware does not require touching the collector.
+type Backend interface {
+	// Name identifies the backend in logs and metrics (e.g. "ssacli").
+	Name() string
+
+	// ListControllers enumerates the controllers present on the host,
+	// including their arrays and drives.
+	ListControllers(ctx context.Context) ([]*Controller, error)
+
+	// ControllerStatus returns the controller-level stats (status, cache,
+	// battery, ...) for a single controller, identified by slot.
+	ControllerStatus(ctx context.Context, slot uint) ([]ArrStat, error)
+}
+
+// DriveDetailer is implemented by backends that can report the richer
+// per-drive telemetry exposed by `pd all show detail` / `ld all show
+// detail` (temperature, power-on hours, rebuild/transform progress, SMART
+// identity fields). Backends that can't provide it yet simply don't
+// implement the interface; Collect type-asserts for it rather than
+// requiring every Backend to fake the data.
+type DriveDetailer interface {
+	DriveDetails(ctx context.Context, slot uint) ([]DriveDetail, []LogicalDriveDetail, error)
+}
+
+// DriveDetail is the detailed telemetry for one physical drive.
+type DriveDetail struct {
+	Id                    string
+	Model                 string
+	SerialNumber          string
+	FirmwareRevision      string
+	TemperatureCelsius    float64
+	TemperatureMaxCelsius float64
+	PowerOnHours          float64
+	RebuildProgressRatio  float64
+	PredictiveFailure     bool
+}
+
+// LogicalDriveDetail is the detailed telemetry for one logical drive.
+type LogicalDriveDetail struct {
+	Id                     string
+	TransformProgressRatio float64
+}
+
+// backendFactories holds the known backend constructors, keyed by the name
+// passed to --backend.
+var backendFactories = map[string]func() (Backend, error){
+	"hpssacli": newSsacliBackend,
+	"ssacli":   newSsacliBackend,
+	"redfish":  newRedfishBackend,
+	"snmp":     newSNMPBackend,
+}
+
+// NewBackend constructs the backend registered under name.
+func NewBackend(name string) (Backend, error) {
+	factory, ok := backendFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return factory()
+}
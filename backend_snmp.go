@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+var (
+	snmpTarget    = flag.String("snmp.target", "localhost", "host to poll over SNMP for the HP CPQIDA MIB")
+	snmpPort      = flag.Uint("snmp.port", 161, "UDP port of the SNMP agent")
+	snmpCommunity = flag.String("snmp.community", "public", "SNMP v2c community string")
+)
+
+// CPQIDA MIB (HP/Compaq Insight Management Agent, Drive Array subtree) OIDs
+// used to read controller model, serial number and condition without
+// needing hpssacli installed on the host. The logical and physical drive
+// status OIDs are declared here but not walked: ListControllers reports
+// each controller with an empty unassigned array and no drives.
+const (
+	cpqDaCntlrModelOID        = "1.3.6.1.4.1.232.3.2.2.1.1.3"
+	cpqDaCntlrSerialNumberOID = "1.3.6.1.4.1.232.3.2.2.1.1.15"
+	cpqDaCntlrConditionOID    = "1.3.6.1.4.1.232.3.2.2.1.1.6"
+	cpqDaLogDrvStatusOID      = "1.3.6.1.4.1.232.3.2.3.1.1.4"
+	cpqDaPhyDrvStatusOID      = "1.3.6.1.4.1.232.3.2.5.1.1.6"
+)
+
+// cpqCondition maps the CPQIDA "condition" enum to the same status strings
+// hpssacli prints, so the rest of the collector can treat every backend the
+// same way.
+var cpqCondition = map[int64]string{
+	1: "other",
+	2: "OK",
+	3: "degraded",
+	4: "failed",
+}
+
+// snmpBackend polls the HP CPQIDA MIB directly, for hosts where the
+// exporter cannot run hpssacli/ssacli but does expose SNMP (e.g. the HP
+// Insight Management Agent installed as a Windows/Linux service).
+//
+// Collect runs one controller per goroutine against the same Backend
+// instance, but *gosnmp.GoSNMP is not safe for concurrent use (Get/BulkWalk
+// share one connection and request-ID bookkeeping with no locking of their
+// own), so every call is serialized through mu.
+type snmpBackend struct {
+	mu     sync.Mutex
+	client *gosnmp.GoSNMP
+}
+
+func newSNMPBackend() (Backend, error) {
+	return newSNMPBackendFor(*snmpTarget, SNMPModule{
+		Community: *snmpCommunity,
+		Port:      uint16(*snmpPort),
+	})
+}
+
+// newSNMPBackendFor builds a snmpBackend for a single probe target, using
+// the community/port from its module rather than the process-wide -snmp.*
+// flags.
+func newSNMPBackendFor(target string, m SNMPModule) (Backend, error) {
+	port := m.Port
+	if port == 0 {
+		port = 161
+	}
+	client := &gosnmp.GoSNMP{
+		Target:    target,
+		Port:      port,
+		Community: m.Community,
+		Version:   gosnmp.Version2c,
+		Timeout:   gosnmp.Default.Timeout,
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("connecting to SNMP agent %s:%d: %w", target, port, err)
+	}
+	return &snmpBackend{client: client}, nil
+}
+
+func (b *snmpBackend) Name() string {
+	return "snmp"
+}
+
+// Close releases the UDP socket opened by Connect. /probe builds a fresh
+// snmpBackend per request, so callers must close it once they're done or
+// the connection is leaked for the life of the process.
+func (b *snmpBackend) Close() error {
+	return b.client.Conn.Close()
+}
+
+// withContext serializes a call against the shared SNMP client and wires
+// ctx into it first, so the scrape's --collect.timeout deadline (or
+// its cancellation) actually bounds the request instead of falling back to
+// the client's own static Timeout.
+func (b *snmpBackend) withContext(ctx context.Context, fn func() error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.client.Context = ctx
+	return fn()
+}
+
+func (b *snmpBackend) ListControllers(ctx context.Context) ([]*Controller, error) {
+	var controllers []*Controller
+
+	err := b.withContext(ctx, func() error {
+		return b.client.BulkWalk(cpqDaCntlrModelOID, func(pdu gosnmp.SnmpPDU) error {
+			slot := uint(len(controllers))
+			ctl := &Controller{
+				Name: fmt.Sprintf("%v", pdu.Value),
+				Type: "snmp",
+				Slot: slot,
+			}
+			ctl.Arrays = []Array{{Id: 'U', Type: "unassigned"}}
+			ctl.CurrentArray = &ctl.Arrays[0]
+			controllers = append(controllers, ctl)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", cpqDaCntlrModelOID, err)
+	}
+
+	for i, ctl := range controllers {
+		var serial *gosnmp.SnmpPacket
+		err := b.withContext(ctx, func() error {
+			var err error
+			serial, err = b.client.Get([]string{fmt.Sprintf("%s.%d", cpqDaCntlrSerialNumberOID, i+1)})
+			return err
+		})
+		if err == nil && len(serial.Variables) == 1 {
+			ctl.SerialNumber = fmt.Sprintf("%v", serial.Variables[0].Value)
+		}
+	}
+
+	return controllers, nil
+}
+
+func (b *snmpBackend) ControllerStatus(ctx context.Context, slot uint) ([]ArrStat, error) {
+	var result *gosnmp.SnmpPacket
+	err := b.withContext(ctx, func() error {
+		var err error
+		result, err = b.client.Get([]string{fmt.Sprintf("%s.%d", cpqDaCntlrConditionOID, slot+1)})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting %s.%d: %w", cpqDaCntlrConditionOID, slot+1, err)
+	}
+
+	var ret []ArrStat
+	for _, v := range result.Variables {
+		var code int64
+		switch val := v.Value.(type) {
+		case int:
+			code = int64(val)
+		case int64:
+			code = val
+		default:
+			continue
+		}
+		status, ok := cpqCondition[code]
+		if !ok {
+			status = "undefined"
+		}
+		ret = append(ret, ArrStat{"status", status})
+	}
+	return ret, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/himorin/hpraid_exporter/parser"
+)
+
+// ssacliBackend drives the exporter the way it has always worked: shelling
+// out to hpssacli (or its ssacli successor) and parsing the human-readable
+// report. It is the default backend, kept for hosts where the exporter runs
+// directly on the machine owning the controllers.
+type ssacliBackend struct {
+	cmd string
+}
+
+func newSsacliBackend() (Backend, error) {
+	return &ssacliBackend{cmd: *cmdName}, nil
+}
+
+func (b *ssacliBackend) Name() string {
+	return b.cmd
+}
+
+func (b *ssacliBackend) ListControllers(ctx context.Context) ([]*Controller, error) {
+	hpinfo, err := exec.CommandContext(ctx, b.cmd, cmdArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("running %s %s: %w", b.cmd, strings.Join(cmdArgs, " "), err)
+	}
+
+	parsed, parseErrs, err := parser.Parse(hpinfo)
+	if err != nil {
+		return nil, err
+	}
+	for _, pe := range parseErrs {
+		fmt.Fprintln(os.Stderr, "hpraid parse error:", pe.Error())
+		hpraidParseErrorsTotal.WithLabelValues(string(pe.Category)).Inc()
+	}
+
+	return parsed.Controller, nil
+}
+
+func (b *ssacliBackend) ControllerStatus(ctx context.Context, slot uint) ([]ArrStat, error) {
+	var ret []ArrStat
+
+	hpinfo, err := b.runForSlot(ctx, ctrlstatArgs, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(hpinfo), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		for name, exp := range ctrlstatRegexp {
+			matched := exp.FindStringSubmatch(line)
+			if len(matched) < 1 {
+				continue
+			}
+			ret = append(ret, ArrStat{name, matched[1]})
+			break
+		}
+	}
+
+	return ret, nil
+}
+
+// runForSlot runs args against the controller in the given slot, where
+// args[1] is the "slot=" prefix filled in with the slot number, mirroring
+// the convention already used by ctrlstatArgs.
+func (b *ssacliBackend) runForSlot(ctx context.Context, args []string, slot uint) ([]byte, error) {
+	cargs := make([]string, len(args))
+	copy(cargs, args)
+	cargs[1] += fmt.Sprint(slot)
+
+	out, err := exec.CommandContext(ctx, b.cmd, cargs...).Output()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "There was an error in running hpssacli command ", err)
+		fmt.Fprintln(os.Stderr, "output ", string(out))
+		return nil, fmt.Errorf("running %s %s: %w", b.cmd, strings.Join(cargs, " "), err)
+	}
+	return out, nil
+}
+
+var (
+	pdDetailArgs = []string{"ctrl", "slot=", "pd", "all", "show", "detail"}
+	ldDetailArgs = []string{"ctrl", "slot=", "ld", "all", "show", "detail"}
+
+	pdHeaderRx = regexp.MustCompile(`^physicaldrive (\S+)`)
+	ldHeaderRx = regexp.MustCompile(`^logicaldrive (\S+)`)
+	detailKVRx = regexp.MustCompile(`^\s+([A-Za-z0-9 /()%.-]+?):\s*(.*)$`)
+)
+
+// DriveDetails runs `pd all show detail` and `ld all show detail` for a
+// controller and returns the richer per-drive telemetry they expose, which
+// `ctrl all show config` does not (temperature, power-on hours, rebuild
+// and transform progress, SMART identity fields).
+func (b *ssacliBackend) DriveDetails(ctx context.Context, slot uint) ([]DriveDetail, []LogicalDriveDetail, error) {
+	pdOut, err := b.runForSlot(ctx, pdDetailArgs, slot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ldOut, err := b.runForSlot(ctx, ldDetailArgs, slot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parsePDDetail(pdOut), parseLDDetail(ldOut), nil
+}
+
+func parsePDDetail(out []byte) []DriveDetail {
+	var drives []DriveDetail
+	var cur *DriveDetail
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := pdHeaderRx.FindStringSubmatch(line); m != nil {
+			drives = append(drives, DriveDetail{Id: m[1]})
+			cur = &drives[len(drives)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		m := detailKVRx.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		switch strings.TrimSpace(m[1]) {
+		case "Model":
+			cur.Model = m[2]
+		case "Serial Number":
+			cur.SerialNumber = m[2]
+		case "Firmware Revision":
+			cur.FirmwareRevision = m[2]
+		case "Current Temperature (C)":
+			cur.TemperatureCelsius, _ = strconv.ParseFloat(m[2], 64)
+		case "Maximum Temperature (C)":
+			cur.TemperatureMaxCelsius, _ = strconv.ParseFloat(m[2], 64)
+		case "Power On Hours":
+			cur.PowerOnHours, _ = strconv.ParseFloat(m[2], 64)
+		case "Percent Rebuild Complete", "Percent Erase Complete":
+			cur.RebuildProgressRatio = parsePercent(m[2])
+		case "Predictive Failure":
+			cur.PredictiveFailure = strings.EqualFold(m[2], "True") || strings.EqualFold(m[2], "Yes")
+		}
+	}
+
+	return drives
+}
+
+func parseLDDetail(out []byte) []LogicalDriveDetail {
+	var drives []LogicalDriveDetail
+	var cur *LogicalDriveDetail
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := ldHeaderRx.FindStringSubmatch(line); m != nil {
+			drives = append(drives, LogicalDriveDetail{Id: m[1]})
+			cur = &drives[len(drives)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		m := detailKVRx.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		if strings.TrimSpace(m[1]) == "Percent Transformation Complete" {
+			cur.TransformProgressRatio = parsePercent(m[2])
+		}
+	}
+
+	return drives
+}
+
+// parsePercent turns "45%" (or "45") into the 0..1 ratio the
+// hpraid_*_progress_ratio metrics use.
+func parsePercent(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	return v / 100
+}
@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+// pdDetailFixture and ldDetailFixture are trimmed captures of
+// `hpssacli/ssacli ctrl slot=N pd all show detail` and `... ld all show
+// detail` output, covering the fields parsePDDetail/parseLDDetail read.
+const pdDetailFixture = `physicaldrive 1I:1:1
+   Port: 1I
+   Box: 1
+   Bay: 1
+   Status: OK
+   Drive Type: Data Drive
+   Model: HP EG0300FCSPN
+   Serial Number: PDNLH0ARC6V1SV00001
+   Firmware Revision: HPD6
+   Current Temperature (C): 30
+   Maximum Temperature (C): 48
+   Power On Hours: 12345
+   Percent Rebuild Complete: 45%
+   Predictive Failure: False
+
+physicaldrive 1I:1:2
+   Port: 1I
+   Box: 1
+   Bay: 2
+   Status: OK
+   Model: HP EG0300FCSPN
+   Serial Number: PDNLH0ARC6V1SV00002
+   Firmware Revision: HPD6
+   Current Temperature (C): 29
+   Maximum Temperature (C): 48
+   Power On Hours: 12000
+   Predictive Failure: True
+`
+
+const ldDetailFixture = `logicaldrive 1
+   Size: 279.4 GB
+   Fault Tolerance: RAID 1
+   Status: OK
+   Percent Transformation Complete: 100%
+
+logicaldrive 2
+   Size: 1.8 TB
+   Fault Tolerance: RAID 0
+   Status: OK
+   Percent Transformation Complete: 62%
+`
+
+func TestParsePDDetail(t *testing.T) {
+	drives := parsePDDetail([]byte(pdDetailFixture))
+	if len(drives) != 2 {
+		t.Fatalf("expected 2 drives, got %d", len(drives))
+	}
+
+	d0 := drives[0]
+	if d0.Id != "1I:1:1" || d0.Model != "HP EG0300FCSPN" || d0.SerialNumber != "PDNLH0ARC6V1SV00001" {
+		t.Fatalf("unexpected drive identity: %+v", d0)
+	}
+	if d0.FirmwareRevision != "HPD6" {
+		t.Fatalf("unexpected firmware revision: %q", d0.FirmwareRevision)
+	}
+	if d0.TemperatureCelsius != 30 || d0.TemperatureMaxCelsius != 48 {
+		t.Fatalf("unexpected temperatures: %+v", d0)
+	}
+	if d0.PowerOnHours != 12345 {
+		t.Fatalf("unexpected power-on hours: %v", d0.PowerOnHours)
+	}
+	if d0.RebuildProgressRatio != 0.45 {
+		t.Fatalf("unexpected rebuild progress: %v", d0.RebuildProgressRatio)
+	}
+	if d0.PredictiveFailure {
+		t.Fatalf("expected first drive to not report predictive failure")
+	}
+
+	d1 := drives[1]
+	if d1.Id != "1I:1:2" {
+		t.Fatalf("unexpected second drive id: %q", d1.Id)
+	}
+	if !d1.PredictiveFailure {
+		t.Fatalf("expected second drive to report predictive failure")
+	}
+	if d1.RebuildProgressRatio != 0 {
+		t.Fatalf("expected second drive to have no rebuild progress reported, got %v", d1.RebuildProgressRatio)
+	}
+}
+
+func TestParseLDDetail(t *testing.T) {
+	drives := parseLDDetail([]byte(ldDetailFixture))
+	if len(drives) != 2 {
+		t.Fatalf("expected 2 logical drives, got %d", len(drives))
+	}
+	if drives[0].Id != "1" || drives[0].TransformProgressRatio != 1 {
+		t.Fatalf("unexpected first logical drive: %+v", drives[0])
+	}
+	if drives[1].Id != "2" || drives[1].TransformProgressRatio != 0.62 {
+		t.Fatalf("unexpected second logical drive: %+v", drives[1])
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	cases := []struct {
+		in   string
+		want float64
+	}{
+		{"45%", 0.45},
+		{"100%", 1},
+		{"0%", 0},
+	}
+	for _, c := range cases {
+		if got := parsePercent(c.in); got != c.want {
+			t.Fatalf("parsePercent(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RedfishModule holds the per-target Redfish credentials and TLS options
+// for a probe module, mirroring the -redfish.* flags used in collector mode.
+type RedfishModule struct {
+	User               string `yaml:"user"`
+	Password           string `yaml:"password"`
+	SystemID           string `yaml:"system_id"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// SNMPModule holds the per-target SNMP options for a probe module.
+type SNMPModule struct {
+	Community string `yaml:"community"`
+	Port      uint16 `yaml:"port"`
+}
+
+// Module describes one named probe configuration: which backend to use
+// against a target and the credentials needed to reach it. Modules are
+// selected with the `module` query parameter on /probe.
+type Module struct {
+	Backend string        `yaml:"backend"`
+	Timeout time.Duration `yaml:"timeout"`
+	Redfish RedfishModule `yaml:"redfish"`
+	SNMP    SNMPModule    `yaml:"snmp"`
+}
+
+// Config is the top-level shape of the --config.file YAML document.
+type Config struct {
+	Modules map[string]Module `yaml:"modules"`
+}
+
+// probeableBackends lists the backends newBackendForTarget knows how to
+// build for a single target. It is a subset of backendFactories: ssacli/
+// hpssacli only make sense against the host the exporter itself runs on,
+// so they're valid for --backend but not for a module's `backend:`.
+var probeableBackends = map[string]bool{
+	"redfish": true,
+	"snmp":    true,
+}
+
+// LoadConfig reads and validates the probe module configuration file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for name, module := range cfg.Modules {
+		if !probeableBackends[module.Backend] {
+			return nil, fmt.Errorf("module %q: backend %q cannot be probed remotely", name, module.Backend)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// newBackendForTarget builds the backend a module asks for, scoped to a
+// single probe target, rather than the process-wide flags used by the
+// long-running collector registered on /metrics.
+func newBackendForTarget(target string, m Module) (Backend, error) {
+	switch m.Backend {
+	case "redfish":
+		return newRedfishBackendFor(target, m.Redfish)
+	case "snmp":
+		return newSNMPBackendFor(target, m.SNMP)
+	default:
+		return nil, fmt.Errorf("backend %q cannot be probed remotely", m.Backend)
+	}
+}
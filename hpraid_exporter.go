@@ -17,70 +17,44 @@ Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"github.com/himorin/hpraid_exporter/parser"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/errgroup"
 	"log"
-	"math"
 	"net/http"
 	"os"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	port    = flag.String("port", ":9327", "port to expose /metrics on")
-	cmdName = flag.String("cmd", "hpssacli", "command, that shows hpraid stats")
-	cmdArgs = []string{"ctrl", "all", "show", "config"}
+	port               = flag.String("port", ":9327", "port to expose /metrics on")
+	cmdName            = flag.String("cmd", "hpssacli", "command, that shows hpraid stats")
+	backendName        = flag.String("backend", "ssacli", "backend used to collect hpraid stats: ssacli, redfish or snmp")
+	collectTimeout     = flag.Duration("collect.timeout", 10*time.Second, "maximum time allowed for one full scrape, overridden per /metrics request by the X-Prometheus-Scrape-Timeout-Seconds header and per /probe module by its timeout")
+	collectConcurrency = flag.Int("collect.concurrency", 4, "maximum number of controllers scraped concurrently")
+	cmdArgs            = []string{"ctrl", "all", "show", "config"}
 )
 
-type Parsed struct {
-	Labels       [][]string
-	Controller   []*Controller
-}
-type ArrStat struct {
-	name         string
-	ret          string
-}
-
-type Controller struct {
-	Name         string
-	Type         string
-	Slot         uint
-	SerialNumber string
-	Arrays       []Array
-	CurrentArray *Array
-}
+// Parsed, Controller, Array and Drive are the shapes every backend reports
+// hpraid stats in; the types themselves live in the parser subpackage so it
+// can build them without depending on package main.
+type Parsed = parser.Parsed
+type Controller = parser.Controller
+type Array = parser.Array
+type Drive = parser.Drive
 
-type Array struct {
-	Id          rune
-	Type        string
-	UnusedSpace uint64
-	Drives      []Drive
-}
-
-type Drive struct {
-	Id       string // index or port:box:bay id, might be redundant
-	RaidMode string
-	Status   string
-	Size     uint64
-	Physical bool
-	// below properties are set only for physical drives
-	Type string
-	Port string
-	Box  uint
-	Bay  uint
+type ArrStat struct {
+	name string
+	ret  string
 }
 
-var ctlRx *regexp.Regexp = regexp.MustCompile("^(.*?) in Slot (\\d+)(.*?)\\(sn: ([^\\)]+)\\)$")
-var arrRx *regexp.Regexp = regexp.MustCompile("^array\\s+([A-Z])\\s+\\(([^,]+),\\s+Unused\\s+Space:([^\\)]+)\\)$")
-var szRx *regexp.Regexp = regexp.MustCompile("^\\s*((\\d+)(\\.\\d+)?)\\s+((K|M|G|T)B)?$")
-var logRx *regexp.Regexp = regexp.MustCompile("^(\\d+)\\s+\\(([^,]+),\\s+([^,]+),\\s+([^\\)]+)\\)$")
-var physRx *regexp.Regexp = regexp.MustCompile("^([^\\s]+)\\s+\\(port\\s+([^:]+):box\\s+([^:]+):bay\\s+(\\d+),\\s+([^,]+),\\s+([^,]+),\\s+([^\\)]+)\\)$")
-
 var ctrlstatArgs []string = []string{"ctrl", "slot=", "show"}
 var ctrlstatRegexp = map[string]*regexp.Regexp {
 	"status":      regexp.MustCompile("Controller Status: (\\w+)"),
@@ -135,363 +109,291 @@ var batstat_id = map[string]float64 {
 	"undefined": 99,
 }
 
-func (ctl *Controller) Describe() string {
-	return fmt.Sprintf("%s in slot %d", ctl.Name, ctl.Slot)
-}
-
-func (arr *Array) Describe() string {
-	return fmt.Sprintf("%c (%s)", arr.Id, arr.Type)
-}
-
-func logn(n, b float64) float64 {
-	return math.Log(n) / math.Log(b)
-}
-
-// this function comes from https://github.com/dustin/go-humanize/blob/master/bytes.go
-// under MIT license
-func convertBytesToHumanReadable(s uint64) string {
-	base := float64(1000)
+// prometheus part
+var (
+	hpraidDesc = prometheus.NewDesc(
+		"hpraid_diskstate",
+		"hpraid disk state",
+		[]string{"controller", "array", "drive_describe", "drive_status"}, nil,
+	)
+	hpraidScrapeErrorDesc = prometheus.NewDesc(
+		"hpraid_scrape_error",
+		"whether the last scrape of the backend failed (1) or not (0)",
+		nil, nil,
+	)
+	hpraidParseErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hpraid_parse_errors_total",
+		Help: "Number of hpssacli/ssacli output lines that could not be parsed, by category",
+	}, []string{"category"})
+
+	hpraidDriveInfoDesc = prometheus.NewDesc(
+		"hpraid_drive_info",
+		"hpraid physical drive identity, always 1",
+		[]string{"controller", "drive", "model", "serial_number", "firmware_revision"}, nil,
+	)
+	hpraidDriveTemperatureDesc = prometheus.NewDesc(
+		"hpraid_drive_temperature_celsius",
+		"hpraid physical drive temperature",
+		[]string{"controller", "drive", "reading"}, nil,
+	)
+	hpraidDrivePowerOnHoursDesc = prometheus.NewDesc(
+		"hpraid_drive_power_on_hours",
+		"hpraid physical drive power-on hours",
+		[]string{"controller", "drive"}, nil,
+	)
+	hpraidDriveRebuildProgressDesc = prometheus.NewDesc(
+		"hpraid_drive_rebuild_progress_ratio",
+		"hpraid physical drive rebuild/erase progress, 0-1",
+		[]string{"controller", "drive"}, nil,
+	)
+	hpraidDrivePredictiveFailureDesc = prometheus.NewDesc(
+		"hpraid_drive_predictive_failure",
+		"hpraid physical drive predictive failure flag, 0 or 1",
+		[]string{"controller", "drive"}, nil,
+	)
+	hpraidLogicalDriveTransformProgressDesc = prometheus.NewDesc(
+		"hpraid_logicaldrive_transform_progress_ratio",
+		"hpraid logical drive transformation progress, 0-1",
+		[]string{"controller", "logicaldrive"}, nil,
+	)
+	hpraidScrapeDurationDesc = prometheus.NewDesc(
+		"hpraid_scrape_duration_seconds",
+		"time the last full scrape of this collector took",
+		nil, nil,
+	)
+	hpraidBackendUpDesc = prometheus.NewDesc(
+		"hpraid_backend_up",
+		"whether the last per-controller backend call succeeded (1) or not (0)",
+		[]string{"controller"}, nil,
+	)
+)
 
-	sizes := []string{"", "KB", "MB", "GB", "TB", "PB", "EB"}
-	if s < 10 {
-		return fmt.Sprintf("%d", s)
-	}
-	e := math.Floor(logn(float64(s), base))
-	suffix := sizes[int(e)]
-	val := math.Floor(float64(s)/math.Pow(base, e)*10+0.5) / 10
-	f := "%.0f%s"
-	if val < 10 {
-		f = "%.1f%s"
-	}
-	return fmt.Sprintf(f, val, suffix)
+type collector struct {
+	backend     Backend
+	timeout     time.Duration
+	concurrency int
 }
 
-func (d *Drive) Describe() string {
-	var driveType, mode string
-	if d.Physical {
-		driveType = "physical"
-		mode = d.Type
-	} else {
-		driveType = "logical"
-		mode = d.RaidMode
+func newCollector(backend Backend, timeout time.Duration, concurrency int) collector {
+	if concurrency < 1 {
+		concurrency = 1
 	}
-
-	return fmt.Sprintf("%s %s (%s, %s)", driveType, d.Id, mode, convertBytesToHumanReadable(d.Size))
+	return collector{backend: backend, timeout: timeout, concurrency: concurrency}
 }
 
-func ControllerParse(s string) *Controller {
-	var ctl Controller
-
-	matched := ctlRx.FindStringSubmatch(s)
-
-	ctl.Name = matched[1]
-	ui, err := strconv.ParseUint(matched[2], 10, 32)
-	if err != nil {
-		panic(err)
-	}
-	ctl.Slot = uint(ui)
-	ctl.Type = matched[3]
-	ctl.SerialNumber = matched[4]
-
-	return &ctl
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hpraidDesc
+	ch <- hpraidScrapeErrorDesc
+	ch <- hpraidDriveInfoDesc
+	ch <- hpraidDriveTemperatureDesc
+	ch <- hpraidDrivePowerOnHoursDesc
+	ch <- hpraidDriveRebuildProgressDesc
+	ch <- hpraidDrivePredictiveFailureDesc
+	ch <- hpraidLogicalDriveTransformProgressDesc
+	ch <- hpraidScrapeDurationDesc
+	ch <- hpraidBackendUpDesc
 }
 
-func convertHumanReadableToBytes(s string) uint64 {
-	matched := szRx.FindStringSubmatch(s)
-	if len(matched) == 0 {
-		panic("no match for " + s)
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(hpraidScrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+	}()
+
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
 	}
-	n, _ := strconv.ParseFloat(matched[1], 64)
 
-	var mul uint64 = 1
-	switch matched[5][0] {
-	case 'E':
-		mul *= 1000
-		fallthrough
-	case 'P':
-		mul *= 1000
-		fallthrough
-	case 'T':
-		mul *= 1000
-		fallthrough
-	case 'G':
-		mul *= 1000
-		fallthrough
-	case 'M':
-		mul *= 1000
-		fallthrough
-	case 'K':
-		mul *= 1000
-	default:
-		panic("Unknown size prefix")
+	controllers, err := c.backend.ListControllers(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "There was an error collecting from backend", c.backend.Name(), ":", err)
+		ch <- prometheus.MustNewConstMetric(hpraidScrapeErrorDesc, prometheus.GaugeValue, 1)
+		ch <- prometheus.MustNewConstMetric(
+			hpraidDesc,
+			prometheus.GaugeValue,
+			0,
+			"NULL", "NULL", "NULL", "NULL",
+		)
+		return
 	}
 
-	return uint64(n * float64(mul))
-}
-
-func ArrayParse(s string) *Array {
-	var arr Array
-
-	matched := arrRx.FindStringSubmatch(s)
-	arr.Id = rune(matched[1][0])
-	arr.Type = matched[2]
-	arr.UnusedSpace = convertHumanReadableToBytes(matched[3])
-
-	return &arr
-}
-
-func DriveParse(s string) *Drive {
-	var d Drive
-	if strings.HasPrefix(s, "logicaldrive") {
-		matched := logRx.FindStringSubmatch(s[len("logicaldrive")+1:])
-
-		d.Id = matched[1]
-		d.Size = convertHumanReadableToBytes(matched[2])
-		d.RaidMode = matched[3]
-		d.Status = matched[4]
-		d.Physical = false
-	} else if strings.HasPrefix(s, "physicaldrive") {
-		matched := physRx.FindStringSubmatch(s[len("physicaldrive")+1:])
-
-		d.Id = matched[1]
-		d.Port = matched[2]
-		ui, err := strconv.ParseUint(matched[3], 10, 32)
-		if err != nil {
-			panic(err)
+	ch <- prometheus.MustNewConstMetric(hpraidScrapeErrorDesc, prometheus.GaugeValue, 0)
+	for _, label := range parser.LabelsFromControllers(controllers) {
+		var cstat = drive_status_id["undefined"]
+		clabel := label[3]
+		if (strings.Index(clabel, ",") > -1) {
+			clabel = clabel[0:strings.Index(clabel, ",")]
 		}
-		d.Box = uint(ui)
-		ui, err = strconv.ParseUint(matched[4], 10, 32)
-		if err != nil {
-			panic(err)
+		if _, ok := drive_status_id[clabel]; ok {
+			cstat = drive_status_id[clabel]
 		}
-		d.Bay = uint(ui)
-		d.Type = matched[5]
-		d.Size = convertHumanReadableToBytes(matched[6])
-		d.Status = matched[7]
-		d.Physical = true
-	} else {
-		panic("cannot determine drive type")
+		ch <- prometheus.MustNewConstMetric(
+			hpraidDesc,
+			prometheus.GaugeValue,
+			cstat,
+			label[0], label[1], label[2], label[3],
+		)
 	}
 
-	return &d
-}
-
-func (ctl *Controller) Add(a *Array) {
-	ctl.Arrays = append(ctl.Arrays, *a)
-	ctl.CurrentArray = &ctl.Arrays[len(ctl.Arrays)-1]
-}
-
-func (arr *Array) Add(d *Drive) {
-	arr.Drives = append(arr.Drives, *d)
+	detailer, hasDetails := c.backend.(DriveDetailer)
+
+	// A hung controller must not block the rest of the scrape, so each
+	// controller is collected on its own goroutine, bounded by
+	// --collect.concurrency and cancelled once --collect.timeout (or the
+	// scrape's X-Prometheus-Scrape-Timeout-Seconds deadline) elapses.
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+	for _, ctrl := range controllers {
+		ctrl := ctrl
+		g.Go(func() error {
+			c.collectController(ch, gctx, ctrl)
+			if hasDetails {
+				c.collectDriveDetails(ch, gctx, detailer, ctrl)
+			}
+			return nil
+		})
+	}
+	g.Wait()
 }
 
-func ArrayStatus(id uint) []ArrStat {
-	var (
-		ret []ArrStat
-		hpinfo []byte
-		err    error
-	)
-	cargs := make([]string, len(ctrlstatArgs));
-	copy(cargs, ctrlstatArgs)
-	cargs[1] += fmt.Sprint(id)
-	hpinfo, err = exec.Command(*cmdName, cargs...).Output()
-	if err == nil {
-		for _, line := range strings.Split(string(hpinfo), "\n") {
-			if len(line) == 0 {
+// collectController emits the controller-level stats (status, cache,
+// battery, ...) and hpraid_backend_up for a single controller.
+func (c collector) collectController(ch chan<- prometheus.Metric, ctx context.Context, ctrl *Controller) {
+	statone, err := c.backend.ControllerStatus(ctx, ctrl.Slot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "There was an error reading controller status for slot", ctrl.Slot, ":", err)
+		ch <- prometheus.MustNewConstMetric(hpraidBackendUpDesc, prometheus.GaugeValue, 0, ctrl.Name)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(hpraidBackendUpDesc, prometheus.GaugeValue, 1, ctrl.Name)
+
+	for _, statone := range statone {
+		var cstat float64
+		var lastbat string
+		lastbat = "0"
+		switch statone.name {
+			case "status":
+				if _, ok := ctrlstat_id[statone.ret]; ok {
+					cstat = ctrlstat_id[statone.ret]
+				} else {
+					cstat = ctrlstat_id["undefined"]
+				}
+			case "scan":
+				if _, ok := scan_id[statone.ret]; ok {
+					cstat = scan_id[statone.ret]
+				} else {
+					cstat = scan_id["undefined"]
+				}
+			case "cache":
+				if _, ok := cache_id[statone.ret]; ok {
+					cstat = cache_id[statone.ret]
+				} else {
+					cstat = cache_id["undefined"]
+				}
+			case "cachetotal":
+				cstat, _ = strconv.ParseFloat(statone.ret, 32)
+			case "cachefree":
+				cstat, _ = strconv.ParseFloat(statone.ret, 32)
+			case "batcount":
+				lastbat = statone.ret
 				continue
-			}
-			for name, exp := range ctrlstatRegexp {
-				matched := exp.FindStringSubmatch(line)
-				if (len(matched) < 1) {
-					continue
+			case "batstat":
+				if _, ok := batstat_id[statone.ret]; ok {
+					cstat = batstat_id[statone.ret]
+				} else {
+					cstat = batstat_id["undefined"]
 				}
-				ret = append(ret, ArrStat{name, matched[1]})
-				break
-			}
+				ch <- prometheus.MustNewConstMetric(ctrlDesc["battery"],
+					prometheus.GaugeValue, cstat, ctrl.Name, lastbat, statone.ret, )
+				continue
+			default:
+				continue
 		}
-	} else {
-		fmt.Fprintln(os.Stderr, "There was an error in running hpssacli command ", err)
-		fmt.Fprintln(os.Stderr, "output ", string(hpinfo))
+		ch <- prometheus.MustNewConstMetric(
+			ctrlDesc[statone.name],
+			prometheus.GaugeValue,
+			cstat, ctrl.Name, statone.ret, )
 	}
-	return ret
 }
 
-func genmetrics(hpinfo []byte) Parsed {
-	var (
-		ret Parsed
-		labels [][]string
-	)
-	var currentController *Controller
-	var controllers []*Controller
-
-	for lineNo, line := range strings.Split(string(hpinfo), "\n") {
-		if len(line) == 0 {
-			continue
-		}
+// collectDriveDetails emits the richer per-drive telemetry (temperature,
+// power-on hours, rebuild/transform progress, SMART identity fields) that
+// `ctrl all show config` does not expose.
+func (c collector) collectDriveDetails(ch chan<- prometheus.Metric, ctx context.Context, detailer DriveDetailer, ctrl *Controller) {
+	drives, logicalDrives, err := detailer.DriveDetails(ctx, ctrl.Slot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "There was an error reading drive details for slot", ctrl.Slot, ":", err)
+		return
+	}
 
-		// count number of trailing spaces
-		var i int
-		for i = 0; i < len(line); i++ {
-			if line[i] != ' ' {
-				break
-			}
+	for _, d := range drives {
+		ch <- prometheus.MustNewConstMetric(hpraidDriveInfoDesc, prometheus.GaugeValue, 1,
+			ctrl.Name, d.Id, d.Model, d.SerialNumber, d.FirmwareRevision)
+		ch <- prometheus.MustNewConstMetric(hpraidDriveTemperatureDesc, prometheus.GaugeValue, d.TemperatureCelsius,
+			ctrl.Name, d.Id, "current")
+		ch <- prometheus.MustNewConstMetric(hpraidDriveTemperatureDesc, prometheus.GaugeValue, d.TemperatureMaxCelsius,
+			ctrl.Name, d.Id, "max")
+		ch <- prometheus.MustNewConstMetric(hpraidDrivePowerOnHoursDesc, prometheus.GaugeValue, d.PowerOnHours,
+			ctrl.Name, d.Id)
+		ch <- prometheus.MustNewConstMetric(hpraidDriveRebuildProgressDesc, prometheus.GaugeValue, d.RebuildProgressRatio,
+			ctrl.Name, d.Id)
+		predictiveFailure := 0.0
+		if d.PredictiveFailure {
+			predictiveFailure = 1
 		}
+		ch <- prometheus.MustNewConstMetric(hpraidDrivePredictiveFailureDesc, prometheus.GaugeValue, predictiveFailure,
+			ctrl.Name, d.Id)
+	}
 
-		switch i {
-		case 0:
-			// parse a controller line
-			currentController = ControllerParse(line[i:])
-
-			// create unassigned array
-			currentController.Arrays = []Array{
-				Array{
-					Id:   'U',
-					Type: "unassigned",
-				},
-			}
-
-			controllers = append(controllers, currentController)
-			break
-		case 3:
-			// Parse only string starting with "array"
-			if strings.HasPrefix(line[i:], "array") {
-				currentController.Add(ArrayParse(line[i:]))
-			}
-		case 6:
-			// add phyisical/logical drive
-			currentController.CurrentArray.Add(DriveParse(line[i:]))
-			break
-		default:
-			panic(fmt.Sprintf("cannot parse line %d with %d trailing spaces:%s", lineNo, i, line))
-
-		}
+	for _, ld := range logicalDrives {
+		ch <- prometheus.MustNewConstMetric(hpraidLogicalDriveTransformProgressDesc, prometheus.GaugeValue,
+			ld.TransformProgressRatio, ctrl.Name, ld.Id)
 	}
+}
 
-	for _, controller := range controllers {
-		for _, array := range controller.Arrays {
-			for _, drive := range array.Drives {
-				label := []string{controller.Describe(), array.Describe(), drive.Describe(), drive.Status}
-				labels = append(labels, label)
+// metricsHandler serves /metrics for the process-wide backend. It builds a
+// fresh registry and collector per request so the scrape timeout can track
+// the Prometheus-supplied X-Prometheus-Scrape-Timeout-Seconds header
+// instead of a single timeout fixed at startup.
+func metricsHandler(backend Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout := *collectTimeout
+		if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+			if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+				timeout = time.Duration(secs * float64(time.Second))
 			}
 		}
-	}
-	ret.Labels = labels
-	ret.Controller = controllers
-	return ret
-}
 
-func GetHPInfo() ([]byte, error) {
-	var (
-		hpinfo []byte
-		err    error
-	)
-	hpinfo, err = exec.Command(*cmdName, cmdArgs...).Output()
-	return hpinfo, err
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(newCollector(backend, timeout, *collectConcurrency))
+		registry.MustRegister(hpraidParseErrorsTotal)
+		registry.MustRegister(probeScrapeDuration, probeBackendLatency)
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
 }
 
-// prometheus part
-var (
-	hpraidDesc = prometheus.NewDesc(
-		"hpraid_diskstate",
-		"hpraid disk state",
-		[]string{"controller", "array", "drive_describe", "drive_status"}, nil,
-	)
-)
+func main() {
+	flag.Parse()
 
-type collector struct {
-}
+	backend, err := NewBackend(*backendName)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func (c collector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- hpraidDesc
-}
+	http.HandleFunc("/metrics", metricsHandler(backend))
 
-func (c collector) Collect(ch chan<- prometheus.Metric) {
-	hpinfo, err := GetHPInfo()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "There was an error in running hpssacli command ", err)
-		ch <- prometheus.MustNewConstMetric(
-			hpraidDesc,
-			prometheus.GaugeValue,
-			0,
-			"NULL", "NULL", "NULL", "NULL",
-		)
-	} else {
-		gm := genmetrics(hpinfo)
-		for _, label := range gm.Labels {
-			var cstat = drive_status_id["undefined"]
-			clabel := label[3]
-			if (strings.Index(clabel, ",") > -1) {
-				clabel = clabel[0:strings.Index(clabel, ",")]
-			}
-			if _, ok := drive_status_id[clabel]; ok {
-				cstat = drive_status_id[clabel]
-			}
-			ch <- prometheus.MustNewConstMetric(
-				hpraidDesc,
-				prometheus.GaugeValue,
-				cstat,
-				label[0], label[1], label[2], label[3],
-			)
-		}
-		for _, ctrl := range gm.Controller {
-			for _, statone := range ArrayStatus(ctrl.Slot) {
-				var cstat float64
-				var lastbat string
-				lastbat = "0"
-				switch statone.name {
-					case "status":
-						if _, ok := ctrlstat_id[statone.ret]; ok {
-							cstat = ctrlstat_id[statone.ret]
-						} else {
-							cstat = ctrlstat_id["undefined"]
-						}
-					case "scan":
-						if _, ok := scan_id[statone.ret]; ok {
-							cstat = scan_id[statone.ret]
-						} else {
-							cstat = scan_id["undefined"]
-						}
-					case "cache":
-						if _, ok := cache_id[statone.ret]; ok {
-							cstat = cache_id[statone.ret]
-						} else {
-							cstat = cache_id["undefined"]
-						}
-					case "cachetotal":
-						cstat, _ = strconv.ParseFloat(statone.ret, 32)
-					case "cachefree":
-						cstat, _ = strconv.ParseFloat(statone.ret, 32)
-					case "batcount":
-						lastbat = statone.ret
-						continue
-					case "batstat":
-						if _, ok := batstat_id[statone.ret]; ok {
-							cstat = batstat_id[statone.ret]
-						} else {
-							cstat = batstat_id["undefined"]
-						}
-						ch <- prometheus.MustNewConstMetric(ctrlDesc["battery"], 
-							prometheus.GaugeValue, cstat, ctrl.Name, lastbat, statone.ret, )
-						continue
-					default:
-						continue
-				}
-				ch <- prometheus.MustNewConstMetric(
-					ctrlDesc[statone.name],
-					prometheus.GaugeValue,
-					cstat, ctrl.Name, statone.ret, )
-			}
+	if *configFile != "" {
+		cfg, err := LoadConfig(*configFile)
+		if err != nil {
+			log.Fatal(err)
 		}
+		probeConfig = cfg
+		http.HandleFunc("/probe", probeHandler)
 	}
-}
-
-func main() {
-	flag.Parse()
 
-	registry := prometheus.NewRegistry()
-	collector := collector{}
-	registry.MustRegister(collector)
-	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>hpssacli Exporter</title></head>
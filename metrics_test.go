@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeBackend is a minimal Backend used to exercise the HTTP handlers
+// end-to-end without shelling out or touching the network.
+type fakeBackend struct {
+	name string
+}
+
+func (b *fakeBackend) Name() string { return b.name }
+
+func (b *fakeBackend) ListControllers(ctx context.Context) ([]*Controller, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) ControllerStatus(ctx context.Context, slot uint) ([]ArrStat, error) {
+	return nil, nil
+}
+
+// TestMetricsHandler_NoRegistryCollision guards against metricsHandler
+// registering two distinct metrics under the same fully-qualified name
+// (client_golang panics on MustRegister in that case): it unconditionally
+// registers probeScrapeDuration/probeBackendLatency alongside the
+// collector, so a naming collision between the two would panic every
+// single /metrics scrape.
+func TestMetricsHandler_NoRegistryCollision(t *testing.T) {
+	handler := metricsHandler(&fakeBackend{name: "fake"})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestProbeHandler_NoRegistryCollision serves /probe against a throwaway
+// registry the same way metricsHandler does for /metrics, so a registration
+// panic on that registry fails the test. probeHandler registers only the
+// collector there; the probe-only histograms live on the /metrics registry.
+func TestProbeHandler_NoRegistryCollision(t *testing.T) {
+	probeConfig = &Config{
+		Modules: map[string]Module{
+			"redfish": {Backend: "redfish"},
+		},
+	}
+	defer func() { probeConfig = nil }()
+
+	req := httptest.NewRequest("GET", "/probe?target=127.0.0.1:0&module=redfish", nil)
+	rec := httptest.NewRecorder()
+
+	probeHandler(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
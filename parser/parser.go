@@ -0,0 +1,371 @@
+// Package parser turns the human-readable report printed by
+// `hpssacli ctrl all show config` (and its `ssacli` successor) into a
+// Parsed tree. Unlike the original implementation it never panics: any
+// line it cannot make sense of is recorded as a ParseError and the scan
+// continues, so a firmware update that tweaks the output format degrades
+// a scrape instead of taking the exporter down.
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseErrorCategory classifies why a line could not be parsed, so callers
+// can alert on the kind of drift rather than just a raw error count.
+type ParseErrorCategory string
+
+const (
+	CategoryUnknownIndent    ParseErrorCategory = "unknown-indent"
+	CategoryBadSizeSuffix    ParseErrorCategory = "bad-size-suffix"
+	CategoryUnknownDriveKind ParseErrorCategory = "unknown-drive-kind"
+	CategoryRegexMiss        ParseErrorCategory = "regex-miss"
+)
+
+// ParseError describes a single line of output the parser skipped.
+type ParseError struct {
+	Line     int
+	Text     string
+	Category ParseErrorCategory
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Category, e.Text)
+}
+
+// categorizedError lets the small parsing helpers below report which
+// ParseErrorCategory a failure belongs to without each caller having to
+// re-derive it from the error text.
+type categorizedError struct {
+	category ParseErrorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string { return e.err.Error() }
+
+func newError(category ParseErrorCategory, format string, args ...interface{}) error {
+	return &categorizedError{category: category, err: fmt.Errorf(format, args...)}
+}
+
+func categoryOf(err error) ParseErrorCategory {
+	if ce, ok := err.(*categorizedError); ok {
+		return ce.category
+	}
+	return CategoryRegexMiss
+}
+
+// Parsed is the result of scanning one `ctrl all show config` report.
+type Parsed struct {
+	Labels     [][]string
+	Controller []*Controller
+}
+
+type Controller struct {
+	Name         string
+	Type         string
+	Slot         uint
+	SerialNumber string
+	Arrays       []Array
+	CurrentArray *Array
+}
+
+type Array struct {
+	Id          rune
+	Type        string
+	UnusedSpace uint64
+	Drives      []Drive
+}
+
+type Drive struct {
+	Id       string // index or port:box:bay id, might be redundant
+	RaidMode string
+	Status   string
+	Size     uint64
+	Physical bool
+	// below properties are set only for physical drives
+	Type string
+	Port string
+	Box  uint
+	Bay  uint
+}
+
+var ctlRx = regexp.MustCompile(`^(.*?) in Slot (\d+)(.*?)\(sn: ([^\)]+)\)$`)
+var arrRx = regexp.MustCompile(`^array\s+([A-Z])\s+\(([^,]+),\s+Unused\s+Space:([^\)]+)\)$`)
+var szRx = regexp.MustCompile(`^\s*((\d+)(\.\d+)?)\s+((K|M|G|T)B)?$`)
+var logRx = regexp.MustCompile(`^(\d+)\s+\(([^,]+),\s+([^,]+),\s+([^\)]+)\)$`)
+var physRx = regexp.MustCompile(`^([^\s]+)\s+\(port\s+([^:]+):box\s+([^:]+):bay\s+(\d+),\s+([^,]+),\s+([^,]+),\s+([^\)]+)\)$`)
+
+func (ctl *Controller) Describe() string {
+	return fmt.Sprintf("%s in slot %d", ctl.Name, ctl.Slot)
+}
+
+func (arr *Array) Describe() string {
+	return fmt.Sprintf("%c (%s)", arr.Id, arr.Type)
+}
+
+func logn(n, b float64) float64 {
+	return math.Log(n) / math.Log(b)
+}
+
+// this function comes from https://github.com/dustin/go-humanize/blob/master/bytes.go
+// under MIT license
+func convertBytesToHumanReadable(s uint64) string {
+	base := float64(1000)
+
+	sizes := []string{"", "KB", "MB", "GB", "TB", "PB", "EB"}
+	if s < 10 {
+		return fmt.Sprintf("%d", s)
+	}
+	e := math.Floor(logn(float64(s), base))
+	suffix := sizes[int(e)]
+	val := math.Floor(float64(s)/math.Pow(base, e)*10+0.5) / 10
+	f := "%.0f%s"
+	if val < 10 {
+		f = "%.1f%s"
+	}
+	return fmt.Sprintf(f, val, suffix)
+}
+
+func (d *Drive) Describe() string {
+	var driveType, mode string
+	if d.Physical {
+		driveType = "physical"
+		mode = d.Type
+	} else {
+		driveType = "logical"
+		mode = d.RaidMode
+	}
+
+	return fmt.Sprintf("%s %s (%s, %s)", driveType, d.Id, mode, convertBytesToHumanReadable(d.Size))
+}
+
+func parseController(s string) (*Controller, error) {
+	var ctl Controller
+
+	matched := ctlRx.FindStringSubmatch(s)
+	if matched == nil {
+		return nil, newError(CategoryRegexMiss, "does not look like a controller line")
+	}
+
+	ctl.Name = matched[1]
+	ui, err := strconv.ParseUint(matched[2], 10, 32)
+	if err != nil {
+		return nil, newError(CategoryRegexMiss, "bad slot number: %w", err)
+	}
+	ctl.Slot = uint(ui)
+	ctl.Type = matched[3]
+	ctl.SerialNumber = matched[4]
+
+	return &ctl, nil
+}
+
+func convertHumanReadableToBytes(s string) (uint64, error) {
+	matched := szRx.FindStringSubmatch(s)
+	if matched == nil {
+		return 0, newError(CategoryBadSizeSuffix, "no match for %q", s)
+	}
+	n, _ := strconv.ParseFloat(matched[1], 64)
+	if matched[5] == "" {
+		return 0, newError(CategoryBadSizeSuffix, "missing size suffix in %q", s)
+	}
+
+	var mul uint64 = 1
+	switch matched[5][0] {
+	case 'E':
+		mul *= 1000
+		fallthrough
+	case 'P':
+		mul *= 1000
+		fallthrough
+	case 'T':
+		mul *= 1000
+		fallthrough
+	case 'G':
+		mul *= 1000
+		fallthrough
+	case 'M':
+		mul *= 1000
+		fallthrough
+	case 'K':
+		mul *= 1000
+	default:
+		return 0, newError(CategoryBadSizeSuffix, "unknown size prefix in %q", s)
+	}
+
+	return uint64(n * float64(mul)), nil
+}
+
+func parseArray(s string) (*Array, error) {
+	var arr Array
+
+	matched := arrRx.FindStringSubmatch(s)
+	if matched == nil {
+		return nil, newError(CategoryRegexMiss, "does not look like an array line")
+	}
+	arr.Id = rune(matched[1][0])
+	arr.Type = matched[2]
+
+	unused, err := convertHumanReadableToBytes(matched[3])
+	if err != nil {
+		return nil, err
+	}
+	arr.UnusedSpace = unused
+
+	return &arr, nil
+}
+
+func parseDrive(s string) (*Drive, error) {
+	var d Drive
+	if strings.HasPrefix(s, "logicaldrive") {
+		matched := logRx.FindStringSubmatch(s[len("logicaldrive")+1:])
+		if matched == nil {
+			return nil, newError(CategoryRegexMiss, "does not look like a logicaldrive line")
+		}
+
+		size, err := convertHumanReadableToBytes(matched[2])
+		if err != nil {
+			return nil, err
+		}
+
+		d.Id = matched[1]
+		d.Size = size
+		d.RaidMode = matched[3]
+		d.Status = matched[4]
+		d.Physical = false
+	} else if strings.HasPrefix(s, "physicaldrive") {
+		matched := physRx.FindStringSubmatch(s[len("physicaldrive")+1:])
+		if matched == nil {
+			return nil, newError(CategoryRegexMiss, "does not look like a physicaldrive line")
+		}
+
+		ui, err := strconv.ParseUint(matched[3], 10, 32)
+		if err != nil {
+			return nil, newError(CategoryRegexMiss, "bad box number: %w", err)
+		}
+		box := uint(ui)
+		ui, err = strconv.ParseUint(matched[4], 10, 32)
+		if err != nil {
+			return nil, newError(CategoryRegexMiss, "bad bay number: %w", err)
+		}
+		bay := uint(ui)
+		size, err := convertHumanReadableToBytes(matched[6])
+		if err != nil {
+			return nil, err
+		}
+
+		d.Id = matched[1]
+		d.Port = matched[2]
+		d.Box = box
+		d.Bay = bay
+		d.Type = matched[5]
+		d.Size = size
+		d.Status = matched[7]
+		d.Physical = true
+	} else {
+		return nil, newError(CategoryUnknownDriveKind, "neither a logicaldrive nor a physicaldrive: %q", s)
+	}
+
+	return &d, nil
+}
+
+func (ctl *Controller) Add(a *Array) {
+	ctl.Arrays = append(ctl.Arrays, *a)
+	ctl.CurrentArray = &ctl.Arrays[len(ctl.Arrays)-1]
+}
+
+func (arr *Array) Add(d *Drive) {
+	arr.Drives = append(arr.Drives, *d)
+}
+
+// Parse scans the output of `ctrl all show config` into a Parsed tree.
+// Lines it cannot understand are reported in the returned []ParseError
+// rather than aborting the scan; the returned error is reserved for
+// conditions that make the whole report unusable.
+func Parse(hpinfo []byte) (*Parsed, []ParseError, error) {
+	var errs []ParseError
+	var currentController *Controller
+	var controllers []*Controller
+
+	for lineNo, line := range strings.Split(string(hpinfo), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+
+		// count number of leading spaces
+		var i int
+		for i = 0; i < len(line); i++ {
+			if line[i] != ' ' {
+				break
+			}
+		}
+
+		switch i {
+		case 0:
+			ctl, err := parseController(line[i:])
+			if err != nil {
+				errs = append(errs, ParseError{Line: lineNo, Text: line, Category: categoryOf(err)})
+				currentController = nil
+				continue
+			}
+
+			// create unassigned array
+			ctl.Arrays = []Array{
+				{Id: 'U', Type: "unassigned"},
+			}
+
+			controllers = append(controllers, ctl)
+			currentController = ctl
+		case 3:
+			if currentController == nil {
+				errs = append(errs, ParseError{Line: lineNo, Text: line, Category: CategoryUnknownIndent})
+				continue
+			}
+			// Parse only string starting with "array"
+			if !strings.HasPrefix(line[i:], "array") {
+				continue
+			}
+			arr, err := parseArray(line[i:])
+			if err != nil {
+				errs = append(errs, ParseError{Line: lineNo, Text: line, Category: categoryOf(err)})
+				continue
+			}
+			currentController.Add(arr)
+		case 6:
+			if currentController == nil || currentController.CurrentArray == nil {
+				errs = append(errs, ParseError{Line: lineNo, Text: line, Category: CategoryUnknownIndent})
+				continue
+			}
+			d, err := parseDrive(line[i:])
+			if err != nil {
+				errs = append(errs, ParseError{Line: lineNo, Text: line, Category: categoryOf(err)})
+				continue
+			}
+			currentController.CurrentArray.Add(d)
+		default:
+			errs = append(errs, ParseError{Line: lineNo, Text: line, Category: CategoryUnknownIndent})
+		}
+	}
+
+	return &Parsed{
+		Labels:     LabelsFromControllers(controllers),
+		Controller: controllers,
+	}, errs, nil
+}
+
+// LabelsFromControllers flattens a controller/array/drive tree into the
+// label tuples used by hpraid_diskstate.
+func LabelsFromControllers(controllers []*Controller) [][]string {
+	var labels [][]string
+	for _, controller := range controllers {
+		for _, array := range controller.Arrays {
+			for _, drive := range array.Drives {
+				label := []string{controller.Describe(), array.Describe(), drive.Describe(), drive.Status}
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
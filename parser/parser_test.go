@@ -0,0 +1,140 @@
+package parser
+
+import "testing"
+
+// hpssacliFixture and ssacliFixture are trimmed captures of
+// `hpssacli ctrl all show config` / `ssacli ctrl all show config` output.
+// The two tools share the same report format, only the product name in
+// the controller line differs.
+const hpssacliFixture = `Smart Array P420i in Slot 0 (Embedded) (sn: PDNLH0ARC6V1SV)
+
+   array A (SAS, Unused Space: 0  MB)
+
+
+      logicaldrive 1 (279.4 GB, RAID 1, OK)
+
+      physicaldrive 1I:1:1 (port 1I:box 1:bay 1, SAS, 300 GB, OK)
+      physicaldrive 1I:1:2 (port 1I:box 1:bay 2, SAS, 300 GB, OK)
+`
+
+const ssacliFixture = `Smart Array E208i-a SR Gen10 in Slot 0 (Embedded) (sn: PEYHC0BRH7V1ES)
+
+   array A (SAS, Unused Space: 0  MB)
+
+
+      logicaldrive 1 (1.8 TB, RAID 0, OK)
+
+      physicaldrive 2I:0:1 (port 2I:box 0:bay 1, SAS, 1.8 TB, OK)
+`
+
+// storcliFixture is a capture of `storcli /c0 show` for an LSI/Avago
+// controller. storcli's tabular format shares nothing with
+// hpssacli/ssacli's indented tree, so every line is expected to come back
+// as a regex-miss ParseError rather than panicking.
+const storcliFixture = `Controller = 0
+Status = Success
+Description = None
+
+Drive /c0/e252/s0 :
+----------------------------------------------------------------------
+EID:Slt DID State DG Size Intf Med SED PI SeSz Model            Sp
+----------------------------------------------------------------------
+252:0    0   Onln   0 278.875 GB SATA SSD N   N  512B INTEL SSDSC2KB038T8 U
+----------------------------------------------------------------------
+`
+
+func TestParse_hpssacli(t *testing.T) {
+	parsed, errs, err := Parse([]byte(hpssacliFixture))
+	if err != nil {
+		t.Fatalf("Parse returned fatal error: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	if len(parsed.Controller) != 1 {
+		t.Fatalf("expected 1 controller, got %d", len(parsed.Controller))
+	}
+
+	ctl := parsed.Controller[0]
+	if ctl.Name != "Smart Array P420i" || ctl.Slot != 0 || ctl.SerialNumber != "PDNLH0ARC6V1SV" {
+		t.Fatalf("unexpected controller: %+v", ctl)
+	}
+	if len(ctl.Arrays) != 2 {
+		t.Fatalf("expected unassigned + A arrays, got %d", len(ctl.Arrays))
+	}
+
+	arrA := ctl.Arrays[1]
+	if len(arrA.Drives) != 3 {
+		t.Fatalf("expected 1 logical + 2 physical drives, got %d", len(arrA.Drives))
+	}
+	if arrA.Drives[0].Physical {
+		t.Fatalf("expected first drive to be logical")
+	}
+	if !arrA.Drives[1].Physical || arrA.Drives[1].Box != 1 || arrA.Drives[1].Bay != 1 {
+		t.Fatalf("unexpected physical drive: %+v", arrA.Drives[1])
+	}
+}
+
+func TestParse_ssacli(t *testing.T) {
+	parsed, errs, err := Parse([]byte(ssacliFixture))
+	if err != nil {
+		t.Fatalf("Parse returned fatal error: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	if len(parsed.Controller) != 1 {
+		t.Fatalf("expected 1 controller, got %d", len(parsed.Controller))
+	}
+	if parsed.Controller[0].Name != "Smart Array E208i-a SR Gen10" {
+		t.Fatalf("unexpected controller name: %q", parsed.Controller[0].Name)
+	}
+}
+
+func TestParse_storcliDoesNotPanic(t *testing.T) {
+	parsed, errs, err := Parse([]byte(storcliFixture))
+	if err != nil {
+		t.Fatalf("Parse returned fatal error: %v", err)
+	}
+	if len(parsed.Controller) != 0 {
+		t.Fatalf("expected no controllers understood from storcli output, got %d", len(parsed.Controller))
+	}
+	if len(errs) == 0 {
+		t.Fatalf("expected storcli's unrelated format to produce parse errors")
+	}
+	for _, e := range errs {
+		if e.Category != CategoryRegexMiss {
+			t.Fatalf("unexpected category for storcli line %q: %s", e.Text, e.Category)
+		}
+	}
+}
+
+func TestConvertHumanReadableToBytes(t *testing.T) {
+	cases := []struct {
+		in   string
+		want uint64
+	}{
+		{"279.4 GB", 279400000000},
+		{"0  MB", 0},
+		{"1.8 TB", 1800000000000},
+	}
+	for _, c := range cases {
+		got, err := convertHumanReadableToBytes(c.in)
+		if err != nil {
+			t.Fatalf("convertHumanReadableToBytes(%q): %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("convertHumanReadableToBytes(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestConvertHumanReadableToBytes_badSuffix(t *testing.T) {
+	_, err := convertHumanReadableToBytes("12 XB")
+	if err == nil {
+		t.Fatalf("expected an error for an unknown size suffix")
+	}
+	if categoryOf(err) != CategoryBadSizeSuffix {
+		t.Fatalf("expected CategoryBadSizeSuffix, got %s", categoryOf(err))
+	}
+}
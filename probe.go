@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	configFile  = flag.String("config.file", "", "YAML file defining probe modules; enables the /probe endpoint")
+	probeConfig *Config
+
+	probeScrapeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                        "hpraid_probe_duration_seconds",
+		Help:                        "Time it took to serve a /probe request",
+		NativeHistogramBucketFactor: 1.1,
+	}, []string{"target", "module"})
+	probeBackendLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                        "hpraid_backend_latency_seconds",
+		Help:                        "Time spent in a single backend call made while serving a probe",
+		NativeHistogramBucketFactor: 1.1,
+	}, []string{"target", "module", "call"})
+)
+
+// timedBackend wraps a Backend so every call made on behalf of a probe
+// request is observed in hpraid_backend_latency_seconds, labeled by target
+// and module.
+type timedBackend struct {
+	Backend
+	target string
+	module string
+}
+
+func (b *timedBackend) ListControllers(ctx context.Context) ([]*Controller, error) {
+	start := time.Now()
+	controllers, err := b.Backend.ListControllers(ctx)
+	probeBackendLatency.WithLabelValues(b.target, b.module, "list_controllers").Observe(time.Since(start).Seconds())
+	return controllers, err
+}
+
+func (b *timedBackend) ControllerStatus(ctx context.Context, slot uint) ([]ArrStat, error) {
+	start := time.Now()
+	stats, err := b.Backend.ControllerStatus(ctx, slot)
+	probeBackendLatency.WithLabelValues(b.target, b.module, "controller_status").Observe(time.Since(start).Seconds())
+	return stats, err
+}
+
+// timedDriveDetailer adds DriveDetailer support to a timedBackend whose
+// wrapped Backend implements it. timedBackend only embeds the Backend
+// interface, so wrapping a DriveDetailer in a plain timedBackend would hide
+// that method from collector.Collect's type assertion; newTimedBackend
+// builds this instead whenever the underlying backend supports it.
+type timedDriveDetailer struct {
+	*timedBackend
+	detailer DriveDetailer
+}
+
+func (b *timedDriveDetailer) DriveDetails(ctx context.Context, slot uint) ([]DriveDetail, []LogicalDriveDetail, error) {
+	start := time.Now()
+	drives, logicalDrives, err := b.detailer.DriveDetails(ctx, slot)
+	probeBackendLatency.WithLabelValues(b.target, b.module, "drive_details").Observe(time.Since(start).Seconds())
+	return drives, logicalDrives, err
+}
+
+// newTimedBackend wraps backend for a probe request, instrumenting every
+// call with hpraid_backend_latency_seconds and preserving DriveDetailer
+// support if the backend provides it.
+func newTimedBackend(backend Backend, target, module string) Backend {
+	tb := &timedBackend{Backend: backend, target: target, module: module}
+	if detailer, ok := backend.(DriveDetailer); ok {
+		return &timedDriveDetailer{timedBackend: tb, detailer: detailer}
+	}
+	return tb
+}
+
+// probeHandler implements the blackbox_exporter-style /probe endpoint: it
+// looks up the requested module, builds a backend scoped to ?target=, and
+// serves its metrics on a throwaway registry so concurrent probes never
+// share collector state.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	target := r.URL.Query().Get("target")
+	moduleName := r.URL.Query().Get("module")
+	if target == "" {
+		http.Error(w, "target parameter is required", http.StatusBadRequest)
+		return
+	}
+	if moduleName == "" {
+		http.Error(w, "module parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	module, ok := probeConfig.Modules[moduleName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+		return
+	}
+
+	backend, err := newBackendForTarget(target, module)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Backends that hold a live connection (e.g. the SNMP backend's UDP
+	// socket) implement io.Closer; /probe builds a fresh backend per
+	// request, so it must also tear it down per request or every scrape
+	// leaks a handle.
+	if closer, ok := backend.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	timeout := module.Timeout
+	if timeout <= 0 {
+		timeout = *collectTimeout
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newCollector(newTimedBackend(backend, target, moduleName), timeout, *collectConcurrency))
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	probeScrapeDuration.WithLabelValues(target, moduleName).Observe(time.Since(start).Seconds())
+}
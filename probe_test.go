@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeDetailingBackend is a Backend that also implements DriveDetailer, used
+// to check that newTimedBackend doesn't hide DriveDetails behind the plain
+// Backend interface.
+type fakeDetailingBackend struct {
+	fakeBackend
+	called bool
+}
+
+func (b *fakeDetailingBackend) DriveDetails(ctx context.Context, slot uint) ([]DriveDetail, []LogicalDriveDetail, error) {
+	b.called = true
+	return nil, nil, nil
+}
+
+func TestNewTimedBackend_ForwardsDriveDetailer(t *testing.T) {
+	backend := &fakeDetailingBackend{fakeBackend: fakeBackend{name: "fake"}}
+
+	wrapped := newTimedBackend(backend, "target", "module")
+
+	detailer, ok := wrapped.(DriveDetailer)
+	if !ok {
+		t.Fatalf("expected newTimedBackend to preserve DriveDetailer, got %T", wrapped)
+	}
+	if _, _, err := detailer.DriveDetails(context.Background(), 0); err != nil {
+		t.Fatalf("DriveDetails: %v", err)
+	}
+	if !backend.called {
+		t.Fatalf("expected DriveDetails to be forwarded to the wrapped backend")
+	}
+}
+
+func TestNewTimedBackend_PlainBackendHasNoDriveDetailer(t *testing.T) {
+	wrapped := newTimedBackend(&fakeBackend{name: "fake"}, "target", "module")
+
+	if _, ok := wrapped.(DriveDetailer); ok {
+		t.Fatalf("expected a backend without DriveDetails to stay a plain Backend once wrapped")
+	}
+}